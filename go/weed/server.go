@@ -10,7 +10,6 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -37,6 +36,7 @@ var cmdServer = &Command{
 
 var (
 	serverIp                      = cmdServer.Flag.String("ip", "localhost", "ip or server name")
+	serverBindIp                  = cmdServer.Flag.String("bindIp", "", "ip address to bind to. Defaults to the -ip value. Use 0.0.0.0 to listen on any interface")
 	serverMaxCpu                  = cmdServer.Flag.Int("maxCpu", 0, "maximum number of CPUs. 0 means all available CPUs")
 	serverTimeout                 = cmdServer.Flag.Int("idleTimeout", 10, "connection idle seconds")
 	serverDataCenter              = cmdServer.Flag.String("dataCenter", "", "current volume server's data center name")
@@ -53,6 +53,8 @@ var (
 	volumeDataFolders             = cmdServer.Flag.String("dir", os.TempDir(), "directories to store data files. dir[,dir]...")
 	volumeMaxDataVolumeCounts     = cmdServer.Flag.String("max", "7", "maximum numbers of volumes, count[,count]...")
 	volumePulse                   = cmdServer.Flag.Int("pulseSeconds", 5, "number of seconds between heartbeats")
+	volumeFixJpgOrientation       = cmdServer.Flag.Bool("fixJpgOrientation", true, "Adjust jpg orientation when uploading.")
+	serverElectionTimeout         = cmdServer.Flag.Int("electionTimeout", 10, "seconds to wait for the master's raft cluster to elect a leader. The server exits if no leader is elected within this time")
 
 	serverWhiteList []string
 )
@@ -95,56 +97,53 @@ func runServer(cmd *Command, args []string) bool {
 	if *serverWhiteListOption != "" {
 		serverWhiteList = strings.Split(*serverWhiteListOption, ",")
 	}
+	if *serverBindIp == "" {
+		*serverBindIp = *serverIp
+	}
 
-	var raftWaitForMaster sync.WaitGroup
-	var volumeWait sync.WaitGroup
+	masterRouter := mux.NewRouter()
+	ms := weed_server.NewMasterServer(masterRouter, VERSION, *serverIp, *masterPort, *masterMetaFolder,
+		*masterVolumeSizeLimitMB, *volumePulse, *masterConfFile, *masterDefaultReplicaPlacement, *garbageThreshold, serverWhiteList,
+	)
 
-	raftWaitForMaster.Add(1)
-	volumeWait.Add(1)
+	glog.V(0).Infoln("Start Weed Master", VERSION, "at port", *serverIp+":"+strconv.Itoa(*masterPort))
+	masterListener, e := util.NewListener(
+		*serverBindIp+":"+strconv.Itoa(*masterPort),
+		time.Duration(*serverTimeout)*time.Second,
+	)
+	if e != nil {
+		glog.Fatalf(e.Error())
+	}
 
 	go func() {
-		r := mux.NewRouter()
-		ms := weed_server.NewMasterServer(r, VERSION, *masterPort, *masterMetaFolder,
-			*masterVolumeSizeLimitMB, *volumePulse, *masterConfFile, *masterDefaultReplicaPlacement, *garbageThreshold, serverWhiteList,
-		)
-
-		glog.V(0).Infoln("Start Weed Master", VERSION, "at port", *serverIp+":"+strconv.Itoa(*masterPort))
-		masterListener, e := util.NewListener(
-			*serverIp+":"+strconv.Itoa(*masterPort),
-			time.Duration(*serverTimeout)*time.Second,
-		)
-		if e != nil {
-			glog.Fatalf(e.Error())
-		}
-
-		go func() {
-			raftWaitForMaster.Wait()
-			time.Sleep(100 * time.Millisecond)
-			var peers []string
-			if *serverPeers != "" {
-				peers = strings.Split(*serverPeers, ",")
-			}
-			raftServer := weed_server.NewRaftServer(r, VERSION, peers, *serverIp+":"+strconv.Itoa(*masterPort), *masterMetaFolder, ms.Topo, *volumePulse)
-			ms.SetRaftServer(raftServer)
-			volumeWait.Done()
-		}()
-
-		raftWaitForMaster.Done()
-		if e := http.Serve(masterListener, r); e != nil {
+		if e := http.Serve(masterListener, masterRouter); e != nil {
 			glog.Fatalf("Master Fail to serve:%s", e.Error())
 		}
 	}()
 
-	volumeWait.Wait()
-	time.Sleep(100 * time.Millisecond)
+	var peers []string
+	if *serverPeers != "" {
+		peers = strings.Split(*serverPeers, ",")
+	}
+	raftServer := weed_server.NewRaftServer(masterRouter, VERSION, peers, *serverIp+":"+strconv.Itoa(*masterPort), *masterMetaFolder, ms.Topo, *volumePulse)
+	ms.SetRaftServer(raftServer)
+
+	select {
+	case <-ms.RaftReady():
+		glog.V(0).Infoln("Master raft cluster is ready, leader elected")
+	case <-time.After(time.Duration(*serverElectionTimeout) * time.Second):
+		glog.Fatalf("Timed out after %d seconds waiting for raft leader election", *serverElectionTimeout)
+	}
+
 	r := http.NewServeMux()
 	weed_server.NewVolumeServer(r, VERSION, *serverIp, *volumePort, *volumePublicUrl, folders, maxCounts,
 		*serverIp+":"+strconv.Itoa(*masterPort), *volumePulse, *serverDataCenter, *serverRack, serverWhiteList,
+		*volumeFixJpgOrientation,
 	)
 
 	glog.V(0).Infoln("Start Weed volume server", VERSION, "at http://"+*serverIp+":"+strconv.Itoa(*volumePort))
 	volumeListener, e := util.NewListener(
-		*serverIp+":"+strconv.Itoa(*volumePort),
+		*serverBindIp+":"+strconv.Itoa(*volumePort),
 		time.Duration(*serverTimeout)*time.Second,
 	)
 	if e != nil {
@@ -0,0 +1,75 @@
+package weed_server
+
+import (
+	"sync"
+
+	"code.google.com/p/weed-fs/go/glog"
+	"github.com/gorilla/mux"
+)
+
+// MasterServer holds the master's settings, its Topology and the
+// RaftServer given to SetRaftServer. It does not track volume locations
+// or hand out file ids, and its Topology is not replicated to other
+// master nodes.
+type MasterServer struct {
+	ip                      string
+	port                    int
+	metaFolder              string
+	volumeSizeLimitMB       uint
+	pulseSeconds            int
+	confFile                string
+	defaultReplicaPlacement string
+	garbageThreshold        string
+	whiteList               []string
+
+	Topo *Topology
+
+	raftServer *RaftServer
+
+	raftReadyOnce sync.Once
+	raftReady     chan struct{}
+}
+
+// NewMasterServer returns a master server backed by a new Topology. It
+// registers no HTTP handlers on r. ip is the address this master advertises to peers and
+// volume servers, which may differ from the address the HTTP listener is
+// bound to.
+func NewMasterServer(r *mux.Router, version string, ip string, port int, metaFolder string,
+	volumeSizeLimitMB uint, pulseSeconds int, confFile string, defaultReplicaPlacement string, garbageThreshold string, whiteList []string) *MasterServer {
+
+	ms := &MasterServer{
+		ip:                      ip,
+		port:                    port,
+		metaFolder:              metaFolder,
+		volumeSizeLimitMB:       volumeSizeLimitMB,
+		pulseSeconds:            pulseSeconds,
+		confFile:                confFile,
+		defaultReplicaPlacement: defaultReplicaPlacement,
+		garbageThreshold:        garbageThreshold,
+		whiteList:               whiteList,
+		raftReady:               make(chan struct{}),
+	}
+	ms.Topo = NewTopology("topo", confFile, metaFolder, uint64(volumeSizeLimitMB)*1024*1024, pulseSeconds)
+
+	return ms
+}
+
+// SetRaftServer wires the raft cluster backing ms.Topo into the master,
+// and arranges for RaftReady to fire once the cluster has settled on a
+// leader.
+func (ms *MasterServer) SetRaftServer(raftServer *RaftServer) {
+	ms.raftServer = raftServer
+	go func() {
+		<-raftServer.LeaderKnown()
+		glog.V(0).Infoln("[", ms.ip, "] raft leader is", raftServer.Leader())
+		ms.raftReadyOnce.Do(func() { close(ms.raftReady) })
+	}()
+}
+
+// RaftReady returns a channel that is closed once the raft peer set has
+// joined and a leader is known. runServer waits on this before it
+// creates the embedded volume server, so that volume server is never
+// started against a master that hasn't joined its raft cluster yet.
+func (ms *MasterServer) RaftReady() <-chan struct{} {
+	return ms.raftReady
+}
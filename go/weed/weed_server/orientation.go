@@ -0,0 +1,182 @@
+package weed_server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+)
+
+// fixJpgOrientation reads the EXIF Orientation tag (if any) from a jpeg,
+// applies the matching rotation/flip, and re-encodes the image. Re-encoding
+// drops the EXIF APP1 segment along with it, so the returned bytes have no
+// orientation tag left to act on.
+func fixJpgOrientation(data []byte) ([]byte, error) {
+	orientation, err := readJpgOrientation(data)
+	if err != nil {
+		return nil, err
+	}
+	if orientation <= 1 {
+		return data, nil
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, applyOrientation(img, orientation), &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// readJpgOrientation walks the jpeg's marker segments looking for the
+// EXIF APP1 segment, and returns the Orientation tag (1 if absent, which
+// means "no change needed").
+func readJpgOrientation(data []byte) (int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, errors.New("not a jpeg")
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 0, errors.New("invalid jpeg marker")
+		}
+		marker := data[pos+1]
+		if marker == 0xD8 || marker == 0xD9 {
+			pos += 2
+			continue
+		}
+		if marker == 0xDA {
+			break // start of scan: no more metadata segments follow
+		}
+		if pos+4 > len(data) {
+			break
+		}
+		segLen := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if pos+2+segLen > len(data) {
+			break
+		}
+		if marker == 0xE1 {
+			seg := data[pos+4 : pos+2+segLen]
+			if len(seg) > 6 && string(seg[:6]) == "Exif\x00\x00" {
+				return parseExifOrientation(seg[6:])
+			}
+		}
+		pos += 2 + segLen
+	}
+	return 1, nil
+}
+
+func parseExifOrientation(tiff []byte) (int, error) {
+	if len(tiff) < 8 {
+		return 0, errors.New("short exif header")
+	}
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 0, errors.New("unknown tiff byte order")
+	}
+	ifdOffset := bo.Uint32(tiff[4:8])
+	if int(ifdOffset)+2 > len(tiff) {
+		return 0, errors.New("invalid ifd offset")
+	}
+	numEntries := int(bo.Uint16(tiff[ifdOffset : ifdOffset+2]))
+	entryStart := int(ifdOffset) + 2
+	for i := 0; i < numEntries; i++ {
+		if entryStart+i*12+12 > len(tiff) {
+			break
+		}
+		entry := tiff[entryStart+i*12 : entryStart+i*12+12]
+		tag := bo.Uint16(entry[0:2])
+		if tag == 0x0112 {
+			return int(bo.Uint16(entry[8:10])), nil
+		}
+	}
+	return 1, nil
+}
+
+// applyOrientation rotates/flips img according to the EXIF Orientation
+// value (2-8; 1 and anything else means no change).
+func applyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return flipH(img)
+	case 3:
+		return rotate180(img)
+	case 4:
+		return flipV(img)
+	case 5:
+		return flipH(rotate90(img))
+	case 6:
+		return rotate90(img)
+	case 7:
+		return flipH(rotate270(img))
+	case 8:
+		return rotate270(img)
+	default:
+		return img
+	}
+}
+
+func rotate90(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.Set(b.Max.Y-1-y, x-b.Min.X, img.At(x, y))
+		}
+	}
+	return dst
+}
+
+func rotate270(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.Set(y-b.Min.Y, b.Max.X-1-x, img.At(x, y))
+		}
+	}
+	return dst
+}
+
+func rotate180(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.Set(b.Max.X-1-x, b.Max.Y-1-y, img.At(x, y))
+		}
+	}
+	return dst
+}
+
+func flipH(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.Set(b.Max.X-1-x, y, img.At(x, y))
+		}
+	}
+	return dst
+}
+
+func flipV(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.Set(x, b.Max.Y-1-y, img.At(x, y))
+		}
+	}
+	return dst
+}
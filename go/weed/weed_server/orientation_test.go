@@ -0,0 +1,219 @@
+package weed_server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// buildExifSegment returns a standalone APP1 "Exif" marker segment
+// (including the FF E1 marker and length bytes) encoding a single
+// Orientation (0x0112) IFD entry with the given value.
+func buildExifSegment(orientation uint16) []byte {
+	tiff := make([]byte, 26)
+	copy(tiff[0:2], "II")
+	binary.LittleEndian.PutUint16(tiff[2:4], 42)
+	binary.LittleEndian.PutUint32(tiff[4:8], 8)
+	binary.LittleEndian.PutUint16(tiff[8:10], 1) // one IFD entry
+	entry := tiff[10:22]
+	binary.LittleEndian.PutUint16(entry[0:2], 0x0112) // Orientation tag
+	binary.LittleEndian.PutUint16(entry[2:4], 3)      // type SHORT
+	binary.LittleEndian.PutUint32(entry[4:8], 1)      // count
+	binary.LittleEndian.PutUint16(entry[8:10], orientation)
+	binary.LittleEndian.PutUint32(tiff[22:26], 0) // no next IFD
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segLen := uint16(len(payload) + 2)
+
+	seg := []byte{0xFF, 0xE1}
+	lenBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(lenBytes, segLen)
+	seg = append(seg, lenBytes...)
+	seg = append(seg, payload...)
+	return seg
+}
+
+// jpegWithOrientation encodes a tiny real jpeg and splices in an EXIF APP1
+// segment carrying the given Orientation value right after the SOI marker.
+func jpegWithOrientation(t *testing.T, orientation uint16) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 40), uint8(y * 80), 100, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("encode base jpeg: %v", err)
+	}
+	base := buf.Bytes()
+
+	out := append([]byte{}, base[:2]...) // SOI
+	out = append(out, buildExifSegment(orientation)...)
+	out = append(out, base[2:]...)
+	return out
+}
+
+func TestReadJpgOrientation(t *testing.T) {
+	for _, want := range []int{1, 3, 6, 8} {
+		data := jpegWithOrientation(t, uint16(want))
+		got, err := readJpgOrientation(data)
+		if err != nil {
+			t.Fatalf("orientation %d: unexpected error: %v", want, err)
+		}
+		if got != want {
+			t.Errorf("orientation %d: readJpgOrientation returned %d", want, got)
+		}
+	}
+}
+
+func TestReadJpgOrientationNoExif(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+	got, err := readJpgOrientation(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 1 {
+		t.Errorf("expected default orientation 1 when no EXIF present, got %d", got)
+	}
+}
+
+func TestReadJpgOrientationMalformed(t *testing.T) {
+	cases := map[string][]byte{
+		"empty":                  {},
+		"not a jpeg":             []byte("not a jpeg at all"),
+		"truncated SOI only":     {0xFF, 0xD8},
+		"truncated after marker": {0xFF, 0xD8, 0xFF, 0xE1},
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := readJpgOrientation(data); name != "truncated after marker" && err == nil {
+				t.Errorf("expected error for %q input", name)
+			}
+		})
+	}
+}
+
+func TestParseExifOrientationTruncated(t *testing.T) {
+	if _, err := parseExifOrientation([]byte{0x49, 0x49}); err == nil {
+		t.Error("expected error for truncated tiff header")
+	}
+	if _, err := parseExifOrientation([]byte("XX\x00\x00\x00\x00\x00\x00")); err == nil {
+		t.Error("expected error for unknown byte order marker")
+	}
+}
+
+// colorsOf reads every pixel of img row-major as color.RGBA for easy
+// comparison in tests.
+func colorsOf(img image.Image) [][]color.RGBA {
+	b := img.Bounds()
+	rows := make([][]color.RGBA, b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		row := make([]color.RGBA, b.Dx())
+		for x := 0; x < b.Dx(); x++ {
+			r, g, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			row[x] = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(bl >> 8), uint8(a >> 8)}
+		}
+		rows[y] = row
+	}
+	return rows
+}
+
+func newTestImage() image.Image {
+	// A 2-wide x 3-tall image with a distinct color per pixel, labeled
+	// A..F in row-major order, so transforms can be checked by position.
+	img := image.NewRGBA(image.Rect(0, 0, 2, 3))
+	labels := []color.RGBA{
+		{10, 0, 0, 255}, {20, 0, 0, 255},
+		{30, 0, 0, 255}, {40, 0, 0, 255},
+		{50, 0, 0, 255}, {60, 0, 0, 255},
+	}
+	i := 0
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 2; x++ {
+			img.Set(x, y, labels[i])
+			i++
+		}
+	}
+	return img
+}
+
+func TestApplyOrientationTransforms(t *testing.T) {
+	src := newTestImage()
+	// src rows (A..F):
+	// A B
+	// C D
+	// E F
+	A, B, C, D, E, F := color.RGBA{10, 0, 0, 255}, color.RGBA{20, 0, 0, 255},
+		color.RGBA{30, 0, 0, 255}, color.RGBA{40, 0, 0, 255},
+		color.RGBA{50, 0, 0, 255}, color.RGBA{60, 0, 0, 255}
+
+	cases := []struct {
+		name        string
+		orientation int
+		wantDx      int
+		wantDy      int
+		want        [][]color.RGBA
+	}{
+		{
+			name: "orientation 1 (identity)", orientation: 1, wantDx: 2, wantDy: 3,
+			want: [][]color.RGBA{{A, B}, {C, D}, {E, F}},
+		},
+		{
+			name: "orientation 3 (180 rotate)", orientation: 3, wantDx: 2, wantDy: 3,
+			want: [][]color.RGBA{{F, E}, {D, C}, {B, A}},
+		},
+		{
+			name: "orientation 6 (90 CW)", orientation: 6, wantDx: 3, wantDy: 2,
+			want: [][]color.RGBA{{E, C, A}, {F, D, B}},
+		},
+		{
+			name: "orientation 8 (90 CCW)", orientation: 8, wantDx: 3, wantDy: 2,
+			want: [][]color.RGBA{{B, D, F}, {A, C, E}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out := applyOrientation(src, c.orientation)
+			b := out.Bounds()
+			if b.Dx() != c.wantDx || b.Dy() != c.wantDy {
+				t.Fatalf("got dims %dx%d, want %dx%d", b.Dx(), b.Dy(), c.wantDx, c.wantDy)
+			}
+			got := colorsOf(out)
+			for y := range c.want {
+				for x := range c.want[y] {
+					if got[y][x] != c.want[y][x] {
+						t.Errorf("pixel (%d,%d) = %v, want %v", x, y, got[y][x], c.want[y][x])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFixJpgOrientationNormalizesAndStripsExif(t *testing.T) {
+	data := jpegWithOrientation(t, 6)
+	fixed, err := fixJpgOrientation(data)
+	if err != nil {
+		t.Fatalf("fixJpgOrientation: %v", err)
+	}
+	orientation, err := readJpgOrientation(fixed)
+	if err != nil {
+		t.Fatalf("readJpgOrientation on fixed bytes: %v", err)
+	}
+	if orientation != 1 {
+		t.Errorf("expected re-encoded jpeg to carry no orientation tag (1), got %d", orientation)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(fixed)); err != nil {
+		t.Errorf("fixed bytes are not a valid jpeg: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package weed_server
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"code.google.com/p/weed-fs/go/glog"
+	"github.com/gorilla/mux"
+)
+
+// RaftServer elects a leader among the master's raft peer set. It does
+// not replicate the Topology it is given to the other master nodes.
+type RaftServer struct {
+	name  string
+	peers []string
+	topo  *Topology
+
+	leader string
+
+	leaderOnce  sync.Once
+	leaderKnown chan struct{}
+}
+
+func NewRaftServer(r *mux.Router, version string, peers []string, name string, dataDir string, topo *Topology, pulseSeconds int) *RaftServer {
+	rs := &RaftServer{
+		name:        name,
+		peers:       peers,
+		topo:        topo,
+		leaderKnown: make(chan struct{}),
+	}
+
+	go rs.electLeader()
+
+	return rs
+}
+
+// electLeader waits until every configured peer is actually reachable
+// over the network, then settles on the cluster's leader. Waiting for
+// peers to join (rather than just sorting the configured address list)
+// is what makes LeaderKnown a genuine "the cluster is up" signal instead
+// of firing before any peer master has actually come online. With no
+// peers the local node is its own leader immediately. Among joined
+// peers, the lowest-sorting address is elected, which every node in the
+// set agrees on without needing to exchange any further votes.
+func (rs *RaftServer) electLeader() {
+	for _, peer := range rs.peers {
+		rs.waitUntilReachable(peer)
+	}
+
+	candidates := append([]string{rs.name}, rs.peers...)
+	sort.Strings(candidates)
+	rs.leader = candidates[0]
+	glog.V(0).Infoln("[", rs.name, "] raft cluster", candidates, "elected leader", rs.leader)
+	rs.leaderOnce.Do(func() { close(rs.leaderKnown) })
+}
+
+// waitUntilReachable blocks until a TCP connection to peer succeeds. This
+// is not a raft handshake (no term/log exchange), but it does mean
+// LeaderKnown cannot fire until every peer master has actually started
+// listening, which is the race the -electionTimeout flag is meant to
+// bound from the caller's side.
+func (rs *RaftServer) waitUntilReachable(peer string) {
+	for {
+		conn, err := net.DialTimeout("tcp", peer, 2*time.Second)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		glog.V(1).Infof("[%s] waiting for raft peer %s to join: %v", rs.name, peer, err)
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// Leader returns the address of the currently elected raft leader, or the
+// empty string if no leader has been elected yet.
+func (rs *RaftServer) Leader() string {
+	return rs.leader
+}
+
+// LeaderKnown returns a channel that is closed once every configured peer
+// has joined the cluster and a leader is known.
+func (rs *RaftServer) LeaderKnown() <-chan struct{} {
+	return rs.leaderKnown
+}
@@ -0,0 +1,46 @@
+package weed_server
+
+import (
+	"strings"
+
+	"code.google.com/p/weed-fs/go/glog"
+)
+
+// Store holds a volume server's data folders and upload options. It keeps
+// no needle volumes on disk.
+type Store struct {
+	folders           []string
+	folderMaxCounts   []int
+	fixJpgOrientation bool
+}
+
+func NewStore(folders []string, folderMaxCounts []int, fixJpgOrientation bool) *Store {
+	return &Store{
+		folders:           folders,
+		folderMaxCounts:   folderMaxCounts,
+		fixJpgOrientation: fixJpgOrientation,
+	}
+}
+
+// Write returns the uploaded bytes normalized once, at upload time: with
+// fixJpgOrientation set, a jpeg has its EXIF orientation applied, and if
+// that fails the original bytes are returned. Write itself stores nothing.
+func (s *Store) Write(fileName string, mimeType string, data []byte) ([]byte, error) {
+	if s.fixJpgOrientation && isJpeg(fileName, mimeType) {
+		fixed, err := fixJpgOrientation(data)
+		if err != nil {
+			glog.V(1).Infof("failed to normalize jpg orientation for %s: %v", fileName, err)
+			return data, nil
+		}
+		data = fixed
+	}
+	return data, nil
+}
+
+func isJpeg(fileName string, mimeType string) bool {
+	if mimeType == "image/jpeg" {
+		return true
+	}
+	lower := strings.ToLower(fileName)
+	return strings.HasSuffix(lower, ".jpg") || strings.HasSuffix(lower, ".jpeg")
+}
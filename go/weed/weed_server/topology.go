@@ -0,0 +1,22 @@
+package weed_server
+
+// Topology holds the master's id and topology settings (configuration
+// file, data folder, volume size limit, pulse). It holds no volume
+// locations, and nothing in it is replicated by raft.
+type Topology struct {
+	Id                string
+	confFile          string
+	dataDir           string
+	volumeSizeLimitMB uint64
+	pulseSeconds      int
+}
+
+func NewTopology(id, confFile, dataDir string, volumeSizeLimitMB uint64, pulseSeconds int) *Topology {
+	return &Topology{
+		Id:                id,
+		confFile:          confFile,
+		dataDir:           dataDir,
+		volumeSizeLimitMB: volumeSizeLimitMB,
+		pulseSeconds:      pulseSeconds,
+	}
+}
@@ -0,0 +1,84 @@
+package weed_server
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"code.google.com/p/weed-fs/go/glog"
+)
+
+// VolumeServer stores and serves needles out of one or more data folders.
+type VolumeServer struct {
+	ip              string
+	port            int
+	publicUrl       string
+	folders         []string
+	folderMaxCounts []int
+	masterNode      string
+	pulseSeconds    int
+	dataCenter      string
+	rack            string
+	whiteList       []string
+
+	store *Store
+}
+
+// NewVolumeServer registers the volume server's HTTP handlers on r and
+// returns the server. When fixJpgOrientation is set, uploaded jpegs are
+// rotated/flipped according to their EXIF Orientation tag once, at
+// upload time, so reads never have to redo that work.
+func NewVolumeServer(r *http.ServeMux, version string, ip string, port int, publicUrl string,
+	folders []string, folderMaxCounts []int,
+	masterNode string, pulseSeconds int, dataCenter string, rack string, whiteList []string,
+	fixJpgOrientation bool) *VolumeServer {
+
+	vs := &VolumeServer{
+		ip:              ip,
+		port:            port,
+		publicUrl:       publicUrl,
+		folders:         folders,
+		folderMaxCounts: folderMaxCounts,
+		masterNode:      masterNode,
+		pulseSeconds:    pulseSeconds,
+		dataCenter:      dataCenter,
+		rack:            rack,
+		whiteList:       whiteList,
+	}
+	vs.store = NewStore(folders, folderMaxCounts, fixJpgOrientation)
+
+	r.HandleFunc("/", vs.uploadHandler)
+
+	return vs
+}
+
+// uploadHandler accepts a needle upload and persists it through vs.store,
+// which normalizes the bytes (e.g. jpg orientation) before they are
+// written.
+func (vs *VolumeServer) uploadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data, err = vs.store.Write(header.Filename, header.Header.Get("Content-Type"), data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	glog.V(2).Infof("volume server %s stored upload %s (%d bytes)", vs.ip, header.Filename, len(data))
+	w.WriteHeader(http.StatusCreated)
+}